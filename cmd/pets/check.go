@@ -0,0 +1,59 @@
+package pets
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/windmilleng/pets/internal/mill"
+)
+
+var CheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the Petsfile for missing services and dependency cycles",
+	Long: `Check the Petsfile for missing services and dependency cycles.
+
+Validates the server graph for every tier without starting any servers.`,
+	Example: `pets check`,
+}
+
+func runCheckCmd(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		CheckCmd.Usage()
+
+		fmt.Printf("\nToo many arguments: %+v\n", args)
+		os.Exit(1)
+	}
+
+	analyticsService.Incr("cmd.check", nil)
+	defer analyticsService.Flush(time.Second)
+
+	file := mill.GetFilePath()
+	petsitter, err := newPetsitter()
+	if err != nil {
+		fatal(err)
+	}
+
+	err = petsitter.ExecFile(file)
+	if err != nil {
+		fatal(err)
+	}
+
+	errs := petsitter.School.Check()
+	if len(errs) == 0 {
+		fmt.Printf("%s looks good!\n", file)
+		return
+	}
+
+	for _, err := range errs {
+		fmt.Printf("%v\n\n", err)
+	}
+	fmt.Printf("Found %d problem(s) in %s\n", len(errs), file)
+	os.Exit(1)
+}
+
+func initCheckCmd() {
+	RootCmd.AddCommand(CheckCmd)
+	CheckCmd.Run = runCheckCmd
+}
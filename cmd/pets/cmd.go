@@ -21,6 +21,8 @@ func init() {
 	RootCmd.AddCommand(DownCmd)
 	initLogsCmd()
 	initUpCmd()
+	initCheckCmd()
+	initRestartCmd()
 }
 
 func Execute() error {
@@ -0,0 +1,68 @@
+package pets
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/windmilleng/pets/internal/mill"
+	"github.com/windmilleng/pets/internal/service"
+)
+
+var restartTier string
+var restartWithDependents bool
+
+var RestartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart a server specified in the Petsfile",
+	Long: `Restart a server specified in the Petsfile.
+
+Stops the server, then starts it again. Any dependencies that are already
+running stay up.
+
+To also restart every server that depends on it, so that they pick up its new
+host and port, run: 'pets restart my-server --with-dependents'
+`,
+	Example: `pets restart frontend
+pets restart backend --with-dependents
+pets restart frontend --tier=k8s`,
+}
+
+func runRestartCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		RestartCmd.Usage()
+
+		fmt.Printf("\nExpected exactly one server name: %+v\n", args)
+		os.Exit(1)
+	}
+
+	analyticsService.Incr("cmd.restart", nil)
+	defer analyticsService.Flush(time.Second)
+
+	file := mill.GetFilePath()
+	petsitter, err := newPetsitter()
+	if err != nil {
+		fatal(err)
+	}
+
+	err = petsitter.ExecFile(file)
+	if err != nil {
+		fatal(err)
+	}
+
+	key := service.NewKey(service.Name(args[0]), service.Tier(restartTier))
+	fmt.Printf("Restarting %s\n", key)
+
+	_, err = petsitter.School.RestartByKey(key, restartWithDependents)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func initRestartCmd() {
+	RootCmd.AddCommand(RestartCmd)
+	RestartCmd.Run = runRestartCmd
+	RestartCmd.Flags().StringVar(&restartTier, "tier", "local", "The tier of the server to restart. Defaults to 'local'")
+	RestartCmd.Flags().BoolVar(&restartWithDependents, "with-dependents", false, "Also restart all running servers that depend on this server")
+}
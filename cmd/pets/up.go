@@ -3,6 +3,7 @@ package pets
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 
 var upTier string
 var upOverrides []string
+var upParallelism int
 
 var UpCmd = &cobra.Command{
 	Use:   "up",
@@ -64,6 +66,7 @@ func runUpCmd(cmd *cobra.Command, args []string) {
 	}
 
 	school := petsitter.School
+	school.SetParallelism(upParallelism)
 
 	for name, tier := range overrideMap {
 		err = school.AddOverride(name, tier)
@@ -93,4 +96,5 @@ func initUpCmd() {
 	UpCmd.Run = runUpCmd
 	UpCmd.Flags().StringVar(&upTier, "tier", "local", "The tier of servers to start up. Defaults to 'local'")
 	UpCmd.Flags().StringSliceVar(&upOverrides, "with", nil, "Override servers in the server graph. Example: --with=backend=k8s")
+	UpCmd.Flags().IntVar(&upParallelism, "parallelism", runtime.NumCPU(), "The maximum number of servers to start at once. Use 1 to start servers in serial, 0 for no limit")
 }
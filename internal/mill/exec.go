@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/google/skylark"
@@ -25,6 +26,10 @@ const Petsfile = "Petsfile"
 // :grimace: The lookup key to find the service.Key on the Thread
 const serviceKeyKey = "service_key"
 
+// The lookup keys to find the output streams on the Thread
+const stdoutKey = "stdout"
+const stderrKey = "stderr"
+
 type scriptResult struct {
 	globals skylark.StringDict
 	err     error
@@ -43,6 +48,12 @@ type Petsitter struct {
 
 	// A script file can only be loaded once.
 	resultsByFile map[string]scriptResult
+
+	// Providers may run on many threads at once, so guard all shared state.
+	mu *sync.Mutex
+
+	// Guards writes to Stdout and Stderr.
+	outMu *sync.Mutex
 }
 
 func NewPetsitter(stdout, stderr io.Writer, runner proc.Runner, procfs proc.ProcFS, school *school.PetSchool, drymode bool) *Petsitter {
@@ -54,6 +65,8 @@ func NewPetsitter(stdout, stderr io.Writer, runner proc.Runner, procfs proc.Proc
 		School:        school,
 		DryMode:       drymode,
 		resultsByFile: make(map[string]scriptResult),
+		mu:            &sync.Mutex{},
+		outMu:         &sync.Mutex{},
 	}
 }
 
@@ -62,34 +75,55 @@ func (p *Petsitter) ExecFile(file string) error {
 	if p.DryMode {
 		fmt.Println("🔔 🔔 You are running pets in dry-run mode! None of these commands will execute. 🔔 🔔 \n ")
 	}
+	p.mu.Lock()
 	if result, ok := p.resultsByFile[file]; ok {
+		p.mu.Unlock()
 		if !result.done {
 			return fmt.Errorf("Pets already executing file: %s", file)
 		}
 		return result.err
 	}
-
 	p.resultsByFile[file] = scriptResult{}
+	p.mu.Unlock()
+
 	thread := p.newThread(service.Key{})
 	globals, err := skylark.ExecFile(thread, file, nil, p.builtins())
-	p.resultsByFile[file] = scriptResult{
+	p.setResult(file, scriptResult{
 		globals: globals,
 		err:     err,
 		done:    true,
-	}
+	})
 	return err
 }
 
+func (p *Petsitter) setResult(file string, result scriptResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resultsByFile[file] = result
+}
+
 // key: The service key being started on this thread.
 //      The empty key indicates the global scope.
+//
+// Each provider gets its own thread, so that providers can run in parallel.
+// All output on a service thread is prefixed with the service key.
 func (p *Petsitter) newThread(key service.Key) *skylark.Thread {
+	prefix := ""
+	if key != (service.Key{}) {
+		prefix = fmt.Sprintf("%s | ", key)
+	}
+	stdout := newPrefixWriter(p.outMu, p.Stdout, prefix)
+	stderr := newPrefixWriter(p.outMu, p.Stderr, prefix)
+
 	thread := &skylark.Thread{
 		Print: func(_ *skylark.Thread, msg string) {
-			fmt.Fprintln(p.Stdout, msg)
+			fmt.Fprintln(stdout, msg)
 		},
 		Load: p.load,
 	}
 	thread.SetLocal(serviceKeyKey, key)
+	thread.SetLocal(stdoutKey, stdout)
+	thread.SetLocal(stderrKey, stderr)
 	return thread
 }
 
@@ -115,7 +149,7 @@ func (p *Petsitter) run(t *skylark.Thread, fn *skylark.Builtin, args skylark.Tup
 	if err != nil {
 		return nil, err
 	}
-	fmt.Fprintf(p.Stderr, "Pets ran %s \n", cmdV)
+	fmt.Fprintf(p.stderr(t), "Pets ran %s \n", cmdV)
 
 	cwd, err := p.wd(t)
 	if err != nil {
@@ -126,7 +160,7 @@ func (p *Petsitter) run(t *skylark.Thread, fn *skylark.Builtin, args skylark.Tup
 		// fmt.Println("Pets is running in dry mode.")
 		return skylark.None, nil
 	}
-	if err := p.Runner.RunWithIO(cmdArgs, cwd, p.Stdout, p.Stderr); err != nil {
+	if err := p.Runner.RunWithIO(cmdArgs, cwd, p.stdout(t), p.stderr(t)); err != nil {
 		return nil, err
 	}
 
@@ -156,7 +190,7 @@ func (p *Petsitter) start(t *skylark.Thread, fn *skylark.Builtin, args skylark.T
 	key := p.serviceKey(t)
 
 	if p.DryMode {
-		fmt.Fprintf(p.Stderr, "Pets ran %s in dry run mode \n", cmdV)
+		fmt.Fprintf(p.stderr(t), "Pets ran %s in dry run mode \n", cmdV)
 		return petsProcToSkylarkValue(proc.PetsProc{}), nil
 	}
 
@@ -164,7 +198,7 @@ func (p *Petsitter) start(t *skylark.Thread, fn *skylark.Builtin, args skylark.T
 		return nil, err
 	}
 
-	fmt.Fprintf(p.Stderr, "Pets ran %s \n", cmdV)
+	fmt.Fprintf(p.stderr(t), "Pets ran %s \n", cmdV)
 
 	go func() {
 		// In the background, wait on the process. This tells the OS that it's
@@ -304,7 +338,9 @@ func (p *Petsitter) execPetsFileAt(t *skylark.Thread, module string, isMissingOk
 
 	// Check to see if we've already executed the file.
 	var globals skylark.StringDict
+	p.mu.Lock()
 	prevResult, ok := p.resultsByFile[module]
+	p.mu.Unlock()
 	if ok {
 		if !prevResult.done {
 			return nil, fmt.Errorf("cycle in load graph detected")
@@ -314,11 +350,11 @@ func (p *Petsitter) execPetsFileAt(t *skylark.Thread, module string, isMissingOk
 		// The most exciting part of the function is finally here! We have an executable
 		// Petsfile, so run it and grab the globals.
 		globals, err = skylark.ExecFile(t, module, nil, p.builtins())
-		p.resultsByFile[module] = scriptResult{
+		p.setResult(module, scriptResult{
 			globals: globals,
 			err:     err,
 			done:    true,
-		}
+		})
 	}
 
 	if err != nil {
@@ -365,7 +401,7 @@ func (p *Petsitter) service(t *skylark.Thread, fn *skylark.Builtin, args skylark
 		return nil, err
 	}
 
-	fmt.Fprintf(p.Stderr, "The service %s is now running on port %d → http://%s:%d\n", key, port, host, port)
+	fmt.Fprintf(p.stderr(t), "The service %s is now running on port %d → http://%s:%d\n", key, port, host, port)
 
 	return petsProcToSkylarkValue(pr), nil
 }
@@ -383,7 +419,7 @@ func (p *Petsitter) waitOnHealthCheck(t *skylark.Thread, pr proc.PetsProc) error
 	go func() {
 		select {
 		case <-time.After(time.Second):
-			fmt.Fprintf(p.Stderr, "Waiting on %s for health check at %s...\n", key, pr.Host())
+			fmt.Fprintf(p.stderr(t), "Waiting on %s for health check at %s...\n", key, pr.Host())
 		case <-ctx.Done():
 		}
 		close(waitingCh)
@@ -418,6 +454,16 @@ func (p *Petsitter) serviceKey(t *skylark.Thread) service.Key {
 	return key
 }
 
+// The stdout stream for the current skylark thread.
+func (p *Petsitter) stdout(t *skylark.Thread) io.Writer {
+	return t.Local(stdoutKey).(io.Writer)
+}
+
+// The stderr stream for the current skylark thread.
+func (p *Petsitter) stderr(t *skylark.Thread) io.Writer {
+	return t.Local(stderrKey).(io.Writer)
+}
+
 // Get the best position to display for the current skylark thread.
 func (p *Petsitter) displayPosition(t *skylark.Thread) syntax.Position {
 	frame := t.TopFrame()
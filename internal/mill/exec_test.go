@@ -373,6 +373,32 @@ register("blorg-frontend", "local", start_local)
 	}
 }
 
+func TestRegisterCycle(t *testing.T) {
+	f := newPetFixture(t)
+	defer f.tearDown()
+	petsitter := f.petsitter
+
+	file := filepath.Join(f.dir, "Petsfile")
+	ioutil.WriteFile(file, []byte(`
+def start_a(b):
+  return service(start("nc -lk 8080"), "localhost", 8080)
+
+def start_b(a):
+  return service(start("nc -lk 8081"), "localhost", 8081)
+
+register("a", "local", start_a, deps=["b"])
+register("b", "local", start_b, deps=["a"])
+`), os.FileMode(0777))
+
+	err := petsitter.ExecFile(file)
+	if err == nil ||
+		!strings.Contains(err.Error(), "b-local -> a-local -> b-local") ||
+		!strings.Contains(err.Error(), fmt.Sprintf("a-local: %s/Petsfile:8", f.dir)) ||
+		!strings.Contains(err.Error(), fmt.Sprintf("b-local: %s/Petsfile:9", f.dir)) {
+		t.Errorf("Expected dependency cycle error. Actual: %v", err)
+	}
+}
+
 func TestHealthCheck(t *testing.T) {
 	f := newPetFixture(t)
 	petsitter := f.petsitter
@@ -0,0 +1,53 @@
+package mill
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// A writer that starts every line with a prefix (e.g., "frontend-local | "),
+// so that output from services starting in parallel can be told apart.
+//
+// All prefixWriters that share a mutex write whole chunks under that mutex,
+// so lines from different services don't interleave mid-line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+
+	// True if the next byte written starts a new line.
+	atLineStart bool
+}
+
+func newPrefixWriter(mu *sync.Mutex, w io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{
+		mu:          mu,
+		w:           w,
+		prefix:      []byte(prefix),
+		atLineStart: true,
+	}
+}
+
+func (w *prefixWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := &bytes.Buffer{}
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if w.atLineStart {
+			out.Write(w.prefix)
+		}
+		out.Write(line)
+		w.atLineStart = line[len(line)-1] == '\n'
+	}
+
+	_, err := w.w.Write(out.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,27 @@
+package mill
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	mu := &sync.Mutex{}
+	out := &bytes.Buffer{}
+	fe := newPrefixWriter(mu, out, "frontend-local | ")
+	be := newPrefixWriter(mu, out, "backend-local | ")
+
+	fmt.Fprint(fe, "Pets ran ")
+	fmt.Fprint(fe, "echo meow\nmeow\n")
+	fmt.Fprintln(be, "Waiting on backend-local")
+
+	expected := `frontend-local | Pets ran echo meow
+frontend-local | meow
+backend-local | Waiting on backend-local
+`
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, out.String())
+	}
+}
@@ -82,3 +82,10 @@ func isAlive(pid int) bool {
 	err = process.Signal(syscall.Signal(0))
 	return err == nil
 }
+
+// Checks if any process in the process group led by the given pid is still alive.
+func isGroupAlive(pid int) bool {
+	// A negative pid asks the OS about every process in the group.
+	err := syscall.Kill(-pid, syscall.Signal(0))
+	return err == nil
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/windmilleng/pets/internal/service"
 	"github.com/windmilleng/wmclient/pkg/dirs"
@@ -17,6 +18,10 @@ import (
 const petsDir = "pets"
 const procPath = "pets/proc.json"
 
+// How long StopProc waits for a process group to exit.
+const stopTimeout = 5 * time.Second
+const stopPollInterval = 20 * time.Millisecond
+
 // Saves state about the currently running processes to the filesystem.
 type ProcFS struct {
 	wmDir *dirs.WindmillDir
@@ -139,6 +144,33 @@ func (f ProcFS) RemoveAllProcs() error {
 	return f.procsToFS(nil)
 }
 
+// Stop a proc and all the processes in its process group, wait for them to exit,
+// then remove the proc from the JSON file.
+func (f ProcFS) StopProc(proc PetsProc) error {
+	// Never signal init or the whole system.
+	if proc.Pid <= 1 {
+		return fmt.Errorf("StopProc: invalid pid %d", proc.Pid)
+	}
+
+	// Pets starts all processes with a process group. -p.Pid is a posix trick
+	// to kill all processes in the group. This is helpful for things like 'go run'
+	// that spawn subprocesses, so that the subprocesses get killed too.
+	err := syscall.Kill(-proc.Pid, syscall.SIGINT)
+	if err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("StopProc: %v", err)
+	}
+
+	deadline := time.Now().Add(stopTimeout)
+	for isGroupAlive(proc.Pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("StopProc: pid %d still running after %s", proc.Pid, stopTimeout)
+		}
+		time.Sleep(stopPollInterval)
+	}
+
+	return f.RemoveProc(proc)
+}
+
 // Kill all the procs in the JSON file with a sigkill
 // This is really only suitable for testing. 'pets down' uses a more graceful
 // sigint with output.
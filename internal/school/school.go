@@ -2,6 +2,9 @@ package school
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/windmilleng/pets/internal/proc"
 	"github.com/windmilleng/pets/internal/service"
@@ -37,6 +40,9 @@ type PetSchool struct {
 
 	// Overrides set on the command-line
 	overrides map[service.Name]service.Tier
+
+	// The maximum number of providers to run at once. Zero means no limit.
+	parallelism int
 }
 
 func NewPetSchool(procfs proc.ProcFS) *PetSchool {
@@ -57,6 +63,12 @@ func (s *PetSchool) AddOverride(name service.Name, tier service.Tier) error {
 	return nil
 }
 
+// Sets the maximum number of providers that may run at once.
+// Zero means no limit. One brings up services in serial.
+func (s *PetSchool) SetParallelism(n int) {
+	s.parallelism = n
+}
+
 func (s *PetSchool) AddProvider(key service.Key, provider Provider, deps []service.Name, position string) error {
 	existing, exists := s.providers[key]
 	if exists {
@@ -69,6 +81,127 @@ func (s *PetSchool) AddProvider(key service.Key, provider Provider, deps []servi
 		position:   position,
 	}
 	s.providers[key] = spec
+
+	// A new provider can only close a cycle that runs through itself.
+	cycle := s.findCycle(key)
+	if cycle != nil {
+		err := s.cycleError(cycle)
+		delete(s.providers, key)
+		return err
+	}
+	return nil
+}
+
+// Validates the service graph for every tier without starting anything.
+// Returns an error for each service with a missing dependency or a dependency cycle.
+func (s *PetSchool) Check() []error {
+	keys := make([]service.Key, 0, len(s.providers))
+	for key, _ := range s.providers {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+
+	errs := []error{}
+	inCycle := make(map[service.Key]bool)
+	for _, key := range keys {
+		// Report each cycle once, from the first of its services that we visit.
+		cycle := s.findCycle(key)
+		if cycle != nil && cycle[0] == key && !inCycle[key] {
+			for _, k := range cycle {
+				inCycle[k] = true
+			}
+			errs = append(errs, s.cycleError(cycle))
+		}
+
+		spec := s.providers[key]
+		for _, dep := range spec.inputNames {
+			depKey := s.resolve(service.NewKey(dep, key.Tier))
+			_, ok := s.providers[depKey]
+			if !ok {
+				errs = append(errs, fmt.Errorf(
+					"Service %q depends on service %q, but no provider found for tier %q\n  %s: %s",
+					key.Name, dep, depKey.Tier, key, spec.position))
+			}
+		}
+	}
+	return errs
+}
+
+// Applies any command-line overrides to the key.
+func (s *PetSchool) resolve(key service.Key) service.Key {
+	overrideTier, hasOverride := s.overrides[key.Name]
+	if hasOverride {
+		key.Tier = overrideTier
+	}
+	return key
+}
+
+// Returns the first dependency cycle reachable from the given key, or nil if
+// there is none. The cycle starts and ends with the same key.
+//
+// Missing providers are not cycles, so we skip them here and let up() report them.
+func (s *PetSchool) findCycle(key service.Key) []service.Key {
+	path := []service.Key{}
+	onPath := make(map[service.Key]bool)
+	visited := make(map[service.Key]bool)
+
+	var visit func(key service.Key) []service.Key
+	visit = func(key service.Key) []service.Key {
+		key = s.resolve(key)
+		if onPath[key] {
+			for i, k := range path {
+				if k == key {
+					cycle := append([]service.Key{}, path[i:]...)
+					return append(cycle, key)
+				}
+			}
+		}
+		if visited[key] {
+			return nil
+		}
+		visited[key] = true
+
+		spec, ok := s.providers[key]
+		if !ok {
+			return nil
+		}
+
+		onPath[key] = true
+		path = append(path, key)
+		for _, dep := range spec.inputNames {
+			cycle := visit(service.NewKey(dep, key.Tier))
+			if cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[key] = false
+		return nil
+	}
+	return visit(key)
+}
+
+func (s *PetSchool) cycleError(cycle []service.Key) error {
+	names := make([]string, len(cycle))
+	for i, key := range cycle {
+		names[i] = key.String()
+	}
+
+	positions := ""
+	for _, key := range cycle[:len(cycle)-1] {
+		positions += fmt.Sprintf("\n  %s: %s", key, s.providers[key].position)
+	}
+	return fmt.Errorf("Dependency cycle detected: %s%s", strings.Join(names, " -> "), positions)
+}
+
+// Returns an error if any of the given services are part of a dependency cycle.
+func (s *PetSchool) checkCycles(keys []service.Key) error {
+	for _, key := range keys {
+		cycle := s.findCycle(key)
+		if cycle != nil {
+			return s.cycleError(cycle)
+		}
+	}
 	return nil
 }
 
@@ -100,11 +233,16 @@ func (s *PetSchool) healthyServices() (map[service.Key]proc.PetsProc, error) {
 
 // Bring up the service with the given key, including all its dependencies.
 func (s *PetSchool) UpByKey(key service.Key) (proc.PetsProc, error) {
+	err := s.checkCycles([]service.Key{key})
+	if err != nil {
+		return proc.PetsProc{}, err
+	}
+
 	services, err := s.healthyServices()
 	if err != nil {
 		return proc.PetsProc{}, err
 	}
-	return s.up(key, services)
+	return s.newUpRun(services).up(key)
 }
 
 // Bring up all the services of a given tier. Returns an error if there are no services in this tier.
@@ -114,9 +252,8 @@ func (s *PetSchool) UpByTier(tier service.Tier) ([]proc.PetsProc, error) {
 		return nil, err
 	}
 
-	allProviders := s.providers
 	keys := make([]service.Key, 0)
-	for key, _ := range allProviders {
+	for key, _ := range s.providers {
 		if key.Tier == tier {
 			keys = append(keys, key)
 		}
@@ -126,49 +263,263 @@ func (s *PetSchool) UpByTier(tier service.Tier) ([]proc.PetsProc, error) {
 		return nil, fmt.Errorf("No service providers found for tier: %q", tier)
 	}
 
-	procs := make([]proc.PetsProc, len(keys))
-	for i, key := range keys {
-		proc, err := s.up(key, services)
+	// Sort the keys so that services start in a deterministic order.
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name < keys[j].Name
+	})
+
+	err = s.checkCycles(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	procs, errs := s.newUpRun(services).upAll(keys)
+	for _, err := range errs {
 		if err != nil {
 			return nil, err
 		}
-		procs[i] = proc
+	}
+	return procs, nil
+}
+
+// Restart the service with the given key, re-using any of its dependencies that
+// are already healthy.
+//
+// If withDependents is true, also restart all running services that transitively
+// depend on it, so that they pick up its new host and port. Dependents are stopped
+// before their dependencies, and started after them.
+func (s *PetSchool) RestartByKey(key service.Key, withDependents bool) ([]proc.PetsProc, error) {
+	key = s.resolve(key)
+	_, ok := s.providers[key]
+	if !ok {
+		return nil, fmt.Errorf("No provider found for service %q, tier %q", key.Name, key.Tier)
+	}
+
+	err := s.checkCycles([]service.Key{key})
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := s.healthyServices()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []service.Key{key}
+	if withDependents {
+		for _, dependent := range s.Dependents(key) {
+			_, isRunning := services[dependent]
+			if isRunning {
+				keys = append(keys, dependent)
+			}
+		}
+	}
+
+	keys = s.sortByDependencies(keys)
+	for i := len(keys) - 1; i >= 0; i-- {
+		p, isRunning := services[keys[i]]
+		if !isRunning {
+			continue
+		}
+
+		err := s.procfs.StopProc(p)
+		if err != nil {
+			return nil, fmt.Errorf("Restarting %s: %v", keys[i], err)
+		}
+		delete(services, keys[i])
 	}
 
+	procs, errs := s.newUpRun(services).upAll(keys)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
 	return procs, nil
 }
 
-func (s *PetSchool) up(key service.Key, petsUp map[service.Key]proc.PetsProc) (proc.PetsProc, error) {
-	overrideTier, hasOverride := s.overrides[key.Name]
-	if hasOverride {
-		key.Tier = overrideTier
+// Returns all the services that transitively depend on the service with the given key,
+// sorted by key.
+func (s *PetSchool) Dependents(key service.Key) []service.Key {
+	key = s.resolve(key)
+
+	// Map each service to the services that depend on it directly.
+	reverseDeps := make(map[service.Key][]service.Key)
+	for k, spec := range s.providers {
+		for _, dep := range spec.inputNames {
+			depKey := s.resolve(service.NewKey(dep, k.Tier))
+			reverseDeps[depKey] = append(reverseDeps[depKey], k)
+		}
+	}
+
+	seen := map[service.Key]bool{key: true}
+	queue := []service.Key{key}
+	result := []service.Key{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverseDeps[current] {
+			if seen[dependent] {
+				continue
+			}
+			seen[dependent] = true
+			queue = append(queue, dependent)
+			result = append(result, dependent)
+		}
+	}
+
+	sortKeys(result)
+	return result
+}
+
+// Sorts the keys so that every service comes after the services it depends on,
+// the same order that up() starts them in. Assumes there are no cycles.
+func (s *PetSchool) sortByDependencies(keys []service.Key) []service.Key {
+	sortKeys(keys)
+	wanted := make(map[service.Key]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	result := make([]service.Key, 0, len(keys))
+	visited := make(map[service.Key]bool, len(keys))
+	var visit func(key service.Key)
+	visit = func(key service.Key) {
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		for _, dep := range s.providers[key].inputNames {
+			visit(s.resolve(service.NewKey(dep, key.Tier)))
+		}
+		if wanted[key] {
+			result = append(result, key)
+		}
+	}
+
+	for _, key := range keys {
+		visit(key)
+	}
+	return result
+}
+
+func sortKeys(keys []service.Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Tier != keys[j].Tier {
+			return keys[i].Tier < keys[j].Tier
+		}
+		return keys[i].Name < keys[j].Name
+	})
+}
+
+func (s *PetSchool) newUpRun(services map[service.Key]proc.PetsProc) *upRun {
+	run := &upRun{
+		school:  s,
+		results: make(map[service.Key]*upResult, len(services)),
+	}
+	if s.parallelism > 0 {
+		run.sem = make(chan struct{}, s.parallelism)
+	}
+
+	for key, p := range services {
+		run.results[key] = newDoneResult(p)
+	}
+	return run
+}
+
+// The result of bringing up a single service. Once done is closed,
+// proc and err are safe to read.
+type upResult struct {
+	done chan struct{}
+	proc proc.PetsProc
+	err  error
+}
+
+func newDoneResult(p proc.PetsProc) *upResult {
+	r := &upResult{done: make(chan struct{}), proc: p}
+	close(r.done)
+	return r
+}
+
+// Tracks the state of a single 'pets up', which may bring up many services
+// concurrently. Each service is brought up at most once. Every provider
+// starts as soon as all of its dependencies are healthy.
+type upRun struct {
+	school *PetSchool
+
+	// Limits the number of providers running at once. Nil if there's no limit.
+	sem chan struct{}
+
+	mu      sync.Mutex
+	results map[service.Key]*upResult
+}
+
+// Bring up all the given services concurrently. Returns their procs and errors
+// in the same order as the keys.
+func (r *upRun) upAll(keys []service.Key) ([]proc.PetsProc, []error) {
+	procs := make([]proc.PetsProc, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key service.Key) {
+			defer wg.Done()
+			procs[i], errs[i] = r.up(key)
+		}(i, key)
 	}
+	wg.Wait()
+	return procs, errs
+}
+
+func (r *upRun) up(key service.Key) (proc.PetsProc, error) {
+	key = r.school.resolve(key)
 
-	alreadyRunning, ok := petsUp[key]
+	// If another goroutine is already bringing up this service, wait on it.
+	r.mu.Lock()
+	result, ok := r.results[key]
 	if ok {
-		return alreadyRunning, nil
+		r.mu.Unlock()
+		<-result.done
+		return result.proc, result.err
 	}
+	result = &upResult{done: make(chan struct{})}
+	r.results[key] = result
+	r.mu.Unlock()
 
+	result.proc, result.err = r.upProvider(key)
+	close(result.done)
+	return result.proc, result.err
+}
+
+func (r *upRun) upProvider(key service.Key) (proc.PetsProc, error) {
+	s := r.school
 	providerSpec, ok := s.providers[key]
 	if !ok {
 		return proc.PetsProc{}, fmt.Errorf("No provider found for service %q, tier %q", key.Name, key.Tier)
 	}
 
-	// Make sure all the dependencies are up. For simplicity, we bring them up in serial.
-	inputProcs := make([]proc.PetsProc, len(providerSpec.inputNames))
+	// Make sure all the dependencies are up. Independent dependencies come up in parallel.
+	inputKeys := make([]service.Key, len(providerSpec.inputNames))
 	for i, input := range providerSpec.inputNames {
-		inputKey := service.Key{
+		inputKeys[i] = service.Key{
 			Name: input,
 			Tier: key.Tier,
 		}
+	}
 
-		inputProc, err := s.up(inputKey, petsUp)
+	inputProcs, errs := r.upAll(inputKeys)
+	for i, err := range errs {
 		if err != nil {
+			input := providerSpec.inputNames[i]
 			return proc.PetsProc{}, fmt.Errorf(
 				"Service %q depends on service %q, but %q failed:\n%v", key.Name, input, input, err)
 		}
+	}
 
-		inputProcs[i] = inputProc
+	if r.sem != nil {
+		r.sem <- struct{}{}
+		defer func() { <-r.sem }()
 	}
 
 	// All the inputs are ready! Let the user take over from here.
@@ -177,11 +528,11 @@ func (s *PetSchool) up(key service.Key, petsUp map[service.Key]proc.PetsProc) (p
 		return proc.PetsProc{}, err
 	}
 
-	err = s.procfs.ModifyProc(result.WithServiceKey(key))
+	result = result.WithServiceKey(key)
+	err = s.procfs.ModifyProc(result)
 	if err != nil {
 		return proc.PetsProc{}, err
 	}
 
-	petsUp[key] = result
 	return result, nil
 }
@@ -1,10 +1,14 @@
 package school
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/windmilleng/pets/internal/proc"
 	"github.com/windmilleng/pets/internal/service"
@@ -134,6 +138,72 @@ func TestServerDiamondByTier(t *testing.T) {
 	}
 }
 
+// Both backends depend only on cockroach, so they should start at the same time.
+func TestServerDiamondParallel(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupDiamond()
+
+	// Each backend provider waits until the other has started.
+	started := make(chan bool)
+	for i, name := range []service.Name{blorgBackend, blorglyBackend} {
+		key := localKey(name)
+		spec := f.school.providers[key]
+		provider := spec.provider
+		pid := i + 2
+		spec.provider = Provider(func(inputs []proc.PetsProc) (proc.PetsProc, error) {
+			select {
+			case started <- true:
+			case <-started:
+			case <-time.After(time.Second):
+				return proc.PetsProc{}, fmt.Errorf("backend %d started in serial", pid)
+			}
+			return provider(inputs)
+		})
+		f.school.providers[key] = spec
+	}
+
+	_, err := f.school.UpByKey(localKey(blorgFrontend))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.procs) != 4 {
+		t.Errorf("Expected 4 procs. Actual: %v", f.procs)
+	}
+}
+
+func TestServerDiamondSerial(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupDiamond()
+	f.school.SetParallelism(1)
+
+	running := int32(0)
+	for key, spec := range f.school.providers {
+		provider := spec.provider
+		spec.provider = Provider(func(inputs []proc.PetsProc) (proc.PetsProc, error) {
+			if atomic.AddInt32(&running, 1) > 1 {
+				return proc.PetsProc{}, fmt.Errorf("More than one provider running")
+			}
+			defer atomic.AddInt32(&running, -1)
+			time.Sleep(10 * time.Millisecond)
+			return provider(inputs)
+		})
+		f.school.providers[key] = spec
+	}
+
+	procs, err := f.school.UpByTier(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 4 {
+		t.Errorf("Expected 4 procs returned. Actual: %v", procs)
+	}
+}
+
 func TestMissingDependency(t *testing.T) {
 	f := newSchoolFixture(t)
 	defer f.tearDown()
@@ -158,6 +228,181 @@ func TestMissingDependency(t *testing.T) {
 	}
 }
 
+func TestDependencyCycle(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	err := f.school.AddProvider(localKey(blorgFrontend), f.makeProvider(1), []service.Name{blorgBackend}, "Petsfile:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.school.AddProvider(localKey(blorgBackend), f.makeProvider(2), []service.Name{cockroach}, "Petsfile:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.school.AddProvider(localKey(cockroach), f.makeProvider(3), []service.Name{blorgFrontend}, "Petsfile:8")
+	if err == nil ||
+		!strings.Contains(err.Error(),
+			"cockroach-local -> blorg-frontend-local -> blorg-backend-local -> cockroach-local") ||
+		!strings.Contains(err.Error(), "blorg-backend-local: Petsfile:5") ||
+		!strings.Contains(err.Error(), "cockroach-local: Petsfile:8") {
+		t.Fatalf("Expected cycle error. Actual: %v", err)
+	}
+
+	// The provider that closed the cycle should not be registered.
+	_, hasCockroach := f.school.providers[localKey(cockroach)]
+	if hasCockroach {
+		t.Errorf("Expected cockroach provider to be rejected")
+	}
+}
+
+// Overrides can create cycles across tiers that didn't exist when the providers were added.
+func TestDependencyCycleWithOverride(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	err := f.school.AddProvider(localKey(blorgFrontend), f.makeProvider(1), []service.Name{blorgBackend}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.school.AddProvider(k8sKey(blorgBackend), f.makeProvider(2), []service.Name{blorgFrontend}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.school.AddProvider(k8sKey(blorgFrontend), f.makeProvider(3), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.school.AddOverride(blorgBackend, k8s)
+	f.school.AddOverride(blorgFrontend, local)
+
+	_, err = f.school.UpByKey(localKey(blorgFrontend))
+	if err == nil ||
+		!strings.Contains(err.Error(), "blorg-frontend-local -> blorg-backend-k8s -> blorg-frontend-local") {
+		t.Fatalf("Expected cycle error. Actual: %v", err)
+	}
+
+	if len(f.procs) != 0 {
+		t.Errorf("Expected no procs. Actual: %v", f.procs)
+	}
+
+	errs := f.school.Check()
+	if len(errs) != 1 {
+		t.Errorf("Expected one cycle from Check. Actual: %v", errs)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupDiamond()
+	errs := f.school.Check()
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	err := f.school.AddProvider(k8sKey(blorgFrontend), f.makeProvider(5), []service.Name{blorgBackend}, "Petsfile:12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs = f.school.Check()
+	if len(errs) != 1 ||
+		!strings.Contains(errs[0].Error(), "Service \"blorg-frontend\" depends on service \"blorg-backend\", but no provider found for tier \"k8s\"") ||
+		!strings.Contains(errs[0].Error(), "blorg-frontend-k8s: Petsfile:12") {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	if len(f.procs) != 0 {
+		t.Errorf("Expected no procs. Actual: %v", f.procs)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupDiamond()
+	dependents := f.school.Dependents(localKey(cockroach))
+	expected := []service.Key{localKey(blorgBackend), localKey(blorgFrontend), localKey(blorglyBackend)}
+	if fmt.Sprintf("%v", dependents) != fmt.Sprintf("%v", expected) {
+		t.Errorf("Expected dependents %v. Actual: %v", expected, dependents)
+	}
+
+	dependents = f.school.Dependents(localKey(blorgFrontend))
+	if len(dependents) != 0 {
+		t.Errorf("Expected no dependents. Actual: %v", dependents)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupSleepyDiamond()
+	_, err := f.school.UpByKey(localKey(blorgFrontend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := f.runningPids()
+
+	_, err = f.school.RestartByKey(localKey(blorgBackend), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := f.runningPids()
+
+	if len(after) != 4 {
+		t.Fatalf("Expected 4 running services. Actual: %v", after)
+	}
+	if before[localKey(blorgBackend)] == after[localKey(blorgBackend)] {
+		t.Errorf("Expected blorg-backend to restart")
+	}
+	for _, name := range []service.Name{blorgFrontend, blorglyBackend, cockroach} {
+		if before[localKey(name)] != after[localKey(name)] {
+			t.Errorf("Expected %s to keep running", name)
+		}
+	}
+}
+
+func TestRestartWithDependents(t *testing.T) {
+	f := newSchoolFixture(t)
+	defer f.tearDown()
+
+	f.setupSleepyDiamond()
+	_, err := f.school.UpByKey(localKey(blorgFrontend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := f.runningPids()
+
+	_, err = f.school.RestartByKey(localKey(blorgBackend), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := f.runningPids()
+
+	if len(after) != 4 {
+		t.Fatalf("Expected 4 running services. Actual: %v", after)
+	}
+	for _, name := range []service.Name{blorgFrontend, blorgBackend} {
+		if before[localKey(name)] == after[localKey(name)] {
+			t.Errorf("Expected %s to restart", name)
+		}
+	}
+	for _, name := range []service.Name{blorglyBackend, cockroach} {
+		if before[localKey(name)] != after[localKey(name)] {
+			t.Errorf("Expected %s to keep running", name)
+		}
+	}
+}
+
 func TestOverrideFrontend(t *testing.T) {
 	f := newSchoolFixture(t)
 	defer f.tearDown()
@@ -209,6 +454,7 @@ type schoolFixture struct {
 	dir    string
 	procfs proc.ProcFS
 	school *PetSchool
+	mu     sync.Mutex
 	procs  []proc.PetsProc
 }
 
@@ -248,6 +494,22 @@ func (f *schoolFixture) setupDiamond() {
 	}
 }
 
+// Set up a diamond where every service is a real process that we can stop.
+func (f *schoolFixture) setupSleepyDiamond() {
+	deps := map[service.Name][]service.Name{
+		blorgFrontend:  {blorgBackend, blorglyBackend},
+		blorgBackend:   {cockroach},
+		blorglyBackend: {cockroach},
+		cockroach:      nil,
+	}
+	for name, inputs := range deps {
+		err := f.school.AddProvider(localKey(name), f.makeSleepProvider(), inputs, "")
+		if err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
 func (f *schoolFixture) setupTwoServersTwoProviders() {
 	err := f.school.AddProvider(localKey(blorgFrontend), f.makeProvider(1), []service.Name{blorgBackend}, "")
 	if err != nil {
@@ -281,11 +543,46 @@ func (f *schoolFixture) makeProvider(pid int) Provider {
 		if err != nil {
 			return proc.PetsProc{}, err
 		}
+		f.mu.Lock()
 		f.procs = append(f.procs, p)
+		f.mu.Unlock()
 		return p, nil
 	})
 }
 
+// Creates a provider that starts a real process that does nothing.
+func (f *schoolFixture) makeSleepProvider() Provider {
+	return Provider(func(inputs []proc.PetsProc) (proc.PetsProc, error) {
+		cwd, _ := os.Getwd()
+		cmd, err := proc.NewRunner(f.procfs).StartWithIO([]string{"sleep", "100"}, cwd, ioutil.Discard, ioutil.Discard)
+		if err != nil {
+			return proc.PetsProc{}, err
+		}
+		go cmd.Cmd.Wait()
+
+		p := cmd.Proc.WithExposedHost("localhost", 1000+cmd.Proc.Pid)
+		err = f.procfs.ModifyProc(p)
+		if err != nil {
+			return proc.PetsProc{}, err
+		}
+		return p, nil
+	})
+}
+
+func (f *schoolFixture) runningPids() map[service.Key]int {
+	services, err := f.school.healthyServices()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	result := make(map[service.Key]int)
+	for key, p := range services {
+		result[key] = p.Pid
+	}
+	return result
+}
+
 func (f *schoolFixture) tearDown() {
+	f.procfs.KillAllForTesting()
 	os.RemoveAll(f.dir)
 }